@@ -0,0 +1,98 @@
+package sloth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Vars returns the route variables matched by the router for the
+// current request, keyed by the names used in the resource's
+// registered path pattern (e.g. the "id" in "/items/{id}").
+func Vars(r *http.Request) map[string]string {
+	return mux.Vars(r)
+}
+
+// Bind decodes a request into dst, a pointer to a struct. Fields
+// tagged `path:"name"` are populated from the route variables (see
+// Vars), fields tagged `query:"name"` from the URL query string, and
+// the request body is decoded into dst using the usual `json:"..."`
+// tags. Path and query values are applied after the body so they take
+// precedence over same-named JSON fields. dst must be a non-nil
+// pointer to a struct.
+func Bind(r *http.Request, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("sloth: Bind destination must be a non-nil pointer to a struct")
+	}
+
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(dst); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	vars := Vars(r)
+	query := r.URL.Query()
+	elem := v.Elem()
+	t := elem.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, ok := field.Tag.Lookup("path"); ok {
+			if value, ok := vars[name]; ok {
+				if err := setField(elem.Field(i), value); err != nil {
+					return fmt.Errorf("sloth: binding path %q: %s", name, err)
+				}
+			}
+		}
+		if name, ok := field.Tag.Lookup("query"); ok {
+			if values, ok := query[name]; ok && len(values) > 0 {
+				if err := setField(elem.Field(i), values[0]); err != nil {
+					return fmt.Errorf("sloth: binding query %q: %s", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setField assigns the string value parsed from a path or query
+// parameter to field, converting it to the field's underlying kind.
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field kind %s", field.Kind())
+	}
+	return nil
+}
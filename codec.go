@@ -0,0 +1,144 @@
+package sloth
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codec encodes response bodies into a particular wire format. An
+// API can have multiple codecs registered via RegisterCodec; the one
+// used for a given request is chosen from the client's Accept header.
+type Codec interface {
+	// Marshal encodes v into the codec's wire format.
+	Marshal(buf []byte, v interface{}) ([]byte, error)
+	// ContentType returns the media type this codec produces, to be
+	// used as the response's Content-Type header.
+	ContentType() string
+	// Accepts reports whether this codec can satisfy the given media
+	// type, as found in a request's Accept header (e.g.
+	// "application/json", "application/*" or "*/*").
+	Accepts(mimeType string) bool
+}
+
+// RegisterCodec adds a Codec to the API so it becomes available for
+// content negotiation. When a request carries no Accept header, the
+// codec whose ContentType equals the API's default content type (see
+// SetDefaultContentType) is used, or the first codec registered if
+// none does. When several codecs satisfy the same Accept entry, the
+// one registered first wins.
+//
+// To add formats beyond the built-in JSONCodec and XMLCodec, such as
+// MsgPack or CBOR, implement the Codec interface around the
+// corresponding marshaler (e.g. github.com/vmihailenco/msgpack or
+// github.com/fxamacker/cbor) and register it the same way:
+//
+//	api.RegisterCodec(msgpackCodec{})
+func (api *API) RegisterCodec(c Codec) {
+	api.codecs = append(api.codecs, c)
+}
+
+// selectCodec picks the registered codec that best matches the
+// request's Accept header. It reports false if no registered codec
+// can satisfy the request.
+func (api *API) selectCodec(request *http.Request) (Codec, bool) {
+	if len(api.codecs) == 0 {
+		return nil, false
+	}
+	accept := request.Header.Get("Accept")
+	if accept == "" {
+		for _, c := range api.codecs {
+			if c.ContentType() == api.defaultContentType {
+				return c, true
+			}
+		}
+		return api.codecs[0], true
+	}
+	for _, entry := range parseAccept(accept) {
+		for _, c := range api.codecs {
+			if c.Accepts(entry.mime) {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+type acceptEntry struct {
+	mime string
+	q    float64
+}
+
+// parseAccept parses an Accept header into media types ordered from
+// most to least preferred, following the q-value weighting of RFC
+// 7231 section 5.3.2. Entries that fail to parse, and entries with
+// q=0 (not acceptable), are skipped.
+func parseAccept(header string) []acceptEntry {
+	parts := strings.Split(header, ",")
+	entries := make([]acceptEntry, 0, len(parts))
+	for _, part := range parts {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		entries = append(entries, acceptEntry{mime: mediaType, q: q})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].q > entries[j].q
+	})
+	return entries
+}
+
+// mimeMatches reports whether accept (a single media type taken from
+// an Accept header) matches contentType, honoring "*/*" and
+// "type/*" wildcards.
+func mimeMatches(contentType, accept string) bool {
+	if accept == "*/*" {
+		return true
+	}
+	ctType, ctSub := splitMime(contentType)
+	acType, acSub := splitMime(accept)
+	if acType != ctType {
+		return false
+	}
+	return acSub == "*" || acSub == ctSub
+}
+
+func splitMime(mimeType string) (string, string) {
+	parts := strings.SplitN(mimeType, "/", 2)
+	if len(parts) != 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// JSONCodec is the built-in Codec that marshals response bodies as
+// indented JSON.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(buf []byte, v interface{}) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// ContentType implements Codec.
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// Accepts implements Codec.
+func (c JSONCodec) Accepts(mimeType string) bool {
+	return mimeMatches(c.ContentType(), mimeType)
+}
@@ -1,11 +1,10 @@
 package sloth
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strings"
 )
 
 // GetSupported is the interface that provides the Get
@@ -44,6 +43,14 @@ type PatchSupported interface {
 	Patch(*http.Request) (int, interface{}, http.Header)
 }
 
+// OptionsSupported is the interface that provides the Options
+// method a resource must support to receive HTTP OPTIONS requests.
+// Resources that do not implement this interface still receive an
+// automatically generated OPTIONS response, see requestHandler.
+type OptionsSupported interface {
+	Options(*http.Request) (int, interface{}, http.Header)
+}
+
 // APIMux interface for arbitrary muxer support (like http.ServeMux).
 type APIMux interface {
 	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) *mux.Route
@@ -61,21 +68,44 @@ type API struct {
 	muxInitialized     bool
 	defaultParseForm   bool
 	defaultContentType string
+	cors               *CORSConfig
+	codecs             []Codec
+	errorLogger        ErrorLogger
+	middleware         []Middleware
+	server             *http.Server
 }
 
 // NewAPI allocates and returns a new API.
 func NewAPI() *API {
-	return &API{defaultParseForm: true, defaultContentType: "application/json"}
+	api := &API{defaultParseForm: true, defaultContentType: "application/json"}
+	api.RegisterCodec(JSONCodec{})
+	return api
 }
 
 func (api *API) requestHandler(resource interface{}) http.HandlerFunc {
 	return func(rw http.ResponseWriter, request *http.Request) {
+		defer api.recoverPanic(rw, request)
+
+		if api.cors != nil {
+			if api.cors.handlePreflight(rw, request, api.allowedMethods(resource)) {
+				return
+			}
+		}
 
 		if api.defaultParseForm && request.ParseForm() != nil {
 			rw.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
+		if request.Method == "GET" {
+			if ranger, ok := resource.(RangeSupported); ok {
+				if rangeHeader := request.Header.Get("Range"); rangeHeader != "" {
+					api.handleRangeRequest(rw, request, ranger, rangeHeader)
+					return
+				}
+			}
+		}
+
 		var handler func(*http.Request) (int, interface{}, http.Header)
 
 		switch request.Method {
@@ -103,6 +133,12 @@ func (api *API) requestHandler(resource interface{}) http.HandlerFunc {
 			if resource, ok := resource.(PatchSupported); ok {
 				handler = resource.Patch
 			}
+		case "OPTIONS":
+			if resource, ok := resource.(OptionsSupported); ok {
+				handler = resource.Options
+			} else {
+				handler = api.optionsHandler(resource)
+			}
 		}
 
 		if handler == nil {
@@ -112,37 +148,103 @@ func (api *API) requestHandler(resource interface{}) http.HandlerFunc {
 
 		code, data, header := handler(request)
 
-		var content []byte
-		var err error
-
-		switch data.(type) {
-		case string:
-			content = []byte(data.(string))
-		case []byte:
-			content = data.([]byte)
-		default:
-			// Encode JSON.
-			content, err = json.MarshalIndent(data, "", "  ")
-			if err == nil && api.defaultContentType != "" {
+		if request.Method == "GET" {
+			if _, ok := resource.(RangeSupported); ok {
 				if header == nil {
-					header = http.Header{"Content-Type": {api.defaultContentType}}
-				} else if header.Get("Content-Type") == "" {
-					header.Set("Content-Type", api.defaultContentType)
+					header = http.Header{}
+				}
+				if header.Get("Accept-Ranges") == "" {
+					header.Set("Accept-Ranges", "bytes")
 				}
 			}
 		}
 
-		if err != nil {
-			rw.WriteHeader(http.StatusInternalServerError)
+		api.respond(rw, request, code, data, header)
+	}
+}
+
+// respond encodes data according to its type (string and []byte
+// bypass encoding, anything else goes through the negotiated Codec),
+// applies CORS headers if configured, and writes the response.
+func (api *API) respond(rw http.ResponseWriter, request *http.Request, code int, data interface{}, header http.Header) {
+	if apiErr, ok := data.(*APIError); ok {
+		code = apiErr.Code
+		data = apiErrorEnvelope{Error: apiErr}
+	}
+
+	var content []byte
+	var err error
+
+	switch data.(type) {
+	case string:
+		content = []byte(data.(string))
+	case []byte:
+		content = data.([]byte)
+	default:
+		rw.Header().Add("Vary", "Accept")
+		codec, ok := api.selectCodec(request)
+		if !ok {
+			rw.WriteHeader(http.StatusNotAcceptable)
 			return
 		}
-		for name, values := range header {
-			for _, value := range values {
-				rw.Header().Add(name, value)
+		content, err = codec.Marshal(nil, data)
+		if err == nil && api.defaultContentType != "" {
+			if header == nil {
+				header = http.Header{"Content-Type": {codec.ContentType()}}
+			} else if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", codec.ContentType())
 			}
 		}
-		rw.WriteHeader(code)
-		rw.Write(content)
+	}
+
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if api.cors != nil {
+		api.cors.setHeaders(rw, request)
+	}
+	for name, values := range header {
+		for _, value := range values {
+			rw.Header().Add(name, value)
+		}
+	}
+	rw.WriteHeader(code)
+	rw.Write(content)
+}
+
+// allowedMethods returns the HTTP methods a resource implements,
+// always including OPTIONS itself.
+func (api *API) allowedMethods(resource interface{}) []string {
+	methods := []string{"OPTIONS"}
+	if _, ok := resource.(GetSupported); ok {
+		methods = append(methods, "GET")
+	}
+	if _, ok := resource.(PostSupported); ok {
+		methods = append(methods, "POST")
+	}
+	if _, ok := resource.(PutSupported); ok {
+		methods = append(methods, "PUT")
+	}
+	if _, ok := resource.(DeleteSupported); ok {
+		methods = append(methods, "DELETE")
+	}
+	if _, ok := resource.(HeadSupported); ok {
+		methods = append(methods, "HEAD")
+	}
+	if _, ok := resource.(PatchSupported); ok {
+		methods = append(methods, "PATCH")
+	}
+	return methods
+}
+
+// optionsHandler synthesizes an OPTIONS response for a resource that
+// does not implement OptionsSupported, listing the methods it does
+// implement in the Allow header.
+func (api *API) optionsHandler(resource interface{}) func(*http.Request) (int, interface{}, http.Header) {
+	return func(*http.Request) (int, interface{}, http.Header) {
+		header := http.Header{"Allow": {strings.Join(api.allowedMethods(resource), ", ")}}
+		return http.StatusOK, "", header
 	}
 }
 
@@ -183,10 +285,13 @@ func (api *API) SetDefaultParseForm(defaultParseForm bool) {
 
 // AddResource adds a new resource to an API. The API will route
 // requests that match one of the given paths to the matching HTTP
-// method on the resource.
+// method on the resource. Paths support gorilla/mux patterns such as
+// "/items/{id:[0-9]+}"; use Vars or Bind inside the resource's
+// handler to read the matched variables.
 func (api *API) AddResource(resource interface{}, paths ...string) {
+	handler := api.wrapGlobalMiddleware(api.requestHandler(resource))
 	for _, path := range paths {
-		api.Mux().HandleFunc(path, api.requestHandler(resource))
+		api.Mux().HandleFunc(path, handler.ServeHTTP)
 	}
 }
 
@@ -194,16 +299,8 @@ func (api *API) AddResource(resource interface{}, paths ...string) {
 // the generated handler function with a give wrapper function to allow
 // to hook in Gzip support and similar.
 func (api *API) AddResourceWithWrapper(resource interface{}, wrapper func(handler http.HandlerFunc) http.HandlerFunc, paths ...string) {
+	handler := api.wrapGlobalMiddleware(wrapper(api.requestHandler(resource)))
 	for _, path := range paths {
-		api.Mux().HandleFunc(path, wrapper(api.requestHandler(resource)))
-	}
-}
-
-// Start causes the API to begin serving requests on the given port.
-func (api *API) Start(port int) error {
-	if !api.muxInitialized {
-		return errors.New("You must add at least one resource to this API.")
+		api.Mux().HandleFunc(path, handler.ServeHTTP)
 	}
-	portString := fmt.Sprintf(":%d", port)
-	return http.ListenAndServe(portString, api.Mux())
 }
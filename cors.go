@@ -0,0 +1,88 @@
+package sloth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig describes how an API should respond to cross-origin
+// requests. Pass it to API.SetCORS to enable CORS handling.
+type CORSConfig struct {
+	// AllowedOrigins is the list of origins allowed to access the API.
+	// Use "*" to allow any origin.
+	AllowedOrigins []string
+	// AllowedHeaders is the list of headers the client is allowed to
+	// send, returned in Access-Control-Allow-Headers.
+	AllowedHeaders []string
+	// AllowCredentials controls the Access-Control-Allow-Credentials
+	// response header.
+	AllowCredentials bool
+	// MaxAge sets how long (in seconds) the results of a preflight
+	// request can be cached. Zero means no Access-Control-Max-Age
+	// header is sent.
+	MaxAge int
+}
+
+// SetCORS enables automatic CORS handling for the API, answering
+// preflight OPTIONS requests and annotating real responses with the
+// appropriate Access-Control-* headers.
+func (api *API) SetCORS(cfg CORSConfig) {
+	api.cors = &cfg
+}
+
+func (cors *CORSConfig) allowedOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range cors.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// setHeaders annotates a real (non-preflight) response with the
+// Access-Control-Allow-Origin and, if configured, the
+// Access-Control-Allow-Credentials headers.
+func (cors *CORSConfig) setHeaders(rw http.ResponseWriter, request *http.Request) {
+	origin := request.Header.Get("Origin")
+	if !cors.allowedOrigin(origin) {
+		return
+	}
+	rw.Header().Set("Access-Control-Allow-Origin", origin)
+	if cors.AllowCredentials {
+		rw.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	rw.Header().Add("Vary", "Origin")
+}
+
+// handlePreflight answers a CORS preflight request directly and
+// reports whether it did so, in which case the caller must not run
+// the normal request handling for this request.
+func (cors *CORSConfig) handlePreflight(rw http.ResponseWriter, request *http.Request, allowedMethods []string) bool {
+	if request.Method != "OPTIONS" || request.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	origin := request.Header.Get("Origin")
+	if !cors.allowedOrigin(origin) {
+		rw.WriteHeader(http.StatusForbidden)
+		return true
+	}
+	header := rw.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+	if len(cors.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+	}
+	if cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if cors.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	}
+	header.Add("Vary", "Origin")
+	rw.WriteHeader(http.StatusOK)
+	return true
+}
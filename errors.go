@@ -0,0 +1,68 @@
+package sloth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// APIError is a structured error a resource can return as the data
+// value of its response (in place of the usual body). requestHandler
+// recognizes it, uses its Code as the HTTP status, and renders it
+// through the active Codec with a stable {"error": {...}} shape.
+type APIError struct {
+	Code    int         `json:"code" xml:"code"`
+	Message string      `json:"message" xml:"message"`
+	Details interface{} `json:"details,omitempty" xml:"details,omitempty"`
+	Cause   error       `json:"-" xml:"-"`
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Cause)
+	}
+	return e.Message
+}
+
+// NewAPIError allocates and returns an APIError with the given status
+// code and message.
+func NewAPIError(code int, message string) *APIError {
+	return &APIError{Code: code, Message: message}
+}
+
+// apiErrorEnvelope is the wire shape an APIError is rendered as.
+type apiErrorEnvelope struct {
+	Error *APIError `json:"error" xml:"error"`
+}
+
+// ErrorLogger receives errors recovered from panics in resource
+// handlers, see API.SetErrorLogger.
+type ErrorLogger func(error)
+
+// SetErrorLogger sets the function called with the error recovered
+// from a panicking resource handler, before the API responds with a
+// generic 500 APIError. The default is to not log at all.
+func (api *API) SetErrorLogger(logger ErrorLogger) {
+	api.errorLogger = logger
+}
+
+// recoverPanic is deferred by requestHandler around resource
+// dispatch. It turns a panic into a 500 APIError response instead of
+// taking down the whole server, logging the recovered value via the
+// configured ErrorLogger.
+func (api *API) recoverPanic(rw http.ResponseWriter, request *http.Request) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	if api.errorLogger != nil {
+		api.errorLogger(err)
+	}
+	apiErr := NewAPIError(http.StatusInternalServerError, "internal server error")
+	apiErr.Cause = err
+	api.respond(rw, request, apiErr.Code, apiErr, nil)
+}
@@ -0,0 +1,168 @@
+package sloth
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Middleware wraps an http.Handler to add cross-cutting behavior
+// (logging, auth, compression, ...) around it. It has the same shape
+// used by most net/http-based routers, so middleware written for
+// other frameworks can usually be reused as is.
+type Middleware func(http.Handler) http.Handler
+
+// Use registers global middleware that wraps every resource handler
+// added with AddResource, AddResourceWithWrapper or
+// AddResourceWithMiddleware, in registration order (the first
+// middleware registered ends up outermost). Middleware runs outside
+// requestHandler, so it observes the final status code written to the
+// response. Middleware is applied when a resource is added, so only
+// resources added after the call to Use are wrapped by it.
+func (api *API) Use(mw func(http.Handler) http.Handler) {
+	api.middleware = append(api.middleware, mw)
+}
+
+// AddResourceWithMiddleware behaves like AddResource but wraps the
+// generated handler with chain, a per-resource middleware stack, in
+// addition to any global middleware registered via Use. Middleware in
+// chain runs closest to the resource; global middleware wraps around
+// it.
+func (api *API) AddResourceWithMiddleware(resource interface{}, chain []Middleware, paths ...string) {
+	var handler http.Handler = api.requestHandler(resource)
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+	handler = api.wrapGlobalMiddleware(handler)
+	for _, path := range paths {
+		api.Mux().HandleFunc(path, handler.ServeHTTP)
+	}
+}
+
+// wrapGlobalMiddleware applies the middleware registered via Use
+// around handler, in registration order.
+func (api *API) wrapGlobalMiddleware(handler http.Handler) http.Handler {
+	for i := len(api.middleware) - 1; i >= 0; i-- {
+		handler = api.middleware[i](handler)
+	}
+	return handler
+}
+
+// statusRecorder wraps an http.ResponseWriter to remember the status
+// code written to it, for middleware (like AccessLog) that needs to
+// observe it after the handler has run.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+type requestIDKey struct{}
+
+// RequestIDHeader is the response header RequestID echoes the
+// generated request id back on.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID is a Middleware that assigns each request a random id,
+// attaches it to the request context (retrieve it with
+// RequestIDFromContext) and echoes it back via RequestIDHeader.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		id := newRequestID()
+		rw.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the request id set by RequestID, or ""
+// if none was set.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+// AccessLog returns a Middleware that logs one line per request via
+// logf (e.g. log.Printf), including method, path, status code and
+// duration.
+func AccessLog(logf func(format string, args ...interface{})) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			logf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start))
+		})
+	}
+}
+
+// gzipResponseWriter makes an http.ResponseWriter write through a
+// gzip.Writer instead of straight to the client.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer io.Writer
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	return w.writer.Write(b)
+}
+
+// Gzip is a Middleware that compresses the response body when the
+// client's Accept-Encoding header allows it. It replaces the ad-hoc
+// wrapper functions previously passed to AddResourceWithWrapper for
+// the same purpose.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Encoding", "gzip")
+		rw.Header().Add("Vary", "Accept-Encoding")
+		gz := gzip.NewWriter(rw)
+		defer gz.Close()
+		next.ServeHTTP(&gzipResponseWriter{ResponseWriter: rw, writer: gz}, r)
+	})
+}
+
+// Recovery is a Middleware that recovers panics in the wrapped
+// handler and responds with a bare 500 instead of taking down the
+// server. Resource handlers added through the API already get
+// equivalent protection around their own dispatch (see APIError);
+// Recovery is for handlers registered outside of sloth's resource
+// model, such as ones added directly to the underlying mux.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if recover() != nil {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Timeout returns a Middleware that cancels the handler after d and
+// responds with 503 Service Unavailable and msg if it has not
+// finished in time. It is a thin wrapper around http.TimeoutHandler.
+func Timeout(d time.Duration, msg string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, msg)
+	}
+}
@@ -0,0 +1,74 @@
+package sloth
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RangeSupported is the interface that provides the Range method a
+// resource must support to serve partial GET responses for a given
+// Range header. unit is the range unit requested (typically "bytes"),
+// from and to are the inclusive bounds. Returning a non-nil error
+// tells requestHandler that the range is not satisfiable, causing a
+// 416 response.
+type RangeSupported interface {
+	Range(r *http.Request, unit string, from, to int64) (int, interface{}, http.Header, error)
+}
+
+// handleRangeRequest parses the Range header and dispatches to the
+// resource's Range method. The response carries the status code the
+// resource returns and an Accept-Ranges header; when that status is
+// 206 Partial Content, a Content-Range header is added as well. It
+// responds 416 Range Not Satisfiable if the header is malformed or the
+// resource rejects it.
+func (api *API) handleRangeRequest(rw http.ResponseWriter, request *http.Request, resource RangeSupported, rangeHeader string) {
+	unit, from, to, ok := parseRange(rangeHeader)
+	if !ok {
+		rw.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	code, data, header, err := resource.Range(request, unit, from, to)
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Accept-Ranges", unit)
+
+	if err != nil {
+		rw.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if code == http.StatusPartialContent {
+		header.Set("Content-Range", fmt.Sprintf("%s %d-%d/*", unit, from, to))
+	}
+
+	api.respond(rw, request, code, data, header)
+}
+
+// parseRange parses a single-range "unit=from-to" Range header value
+// (e.g. "bytes=0-499"). Open-ended and multi-range requests are not
+// supported and are reported as invalid.
+func parseRange(header string) (unit string, from, to int64, ok bool) {
+	unitAndRange := strings.SplitN(header, "=", 2)
+	if len(unitAndRange) != 2 {
+		return "", 0, 0, false
+	}
+	bounds := strings.SplitN(unitAndRange[1], "-", 2)
+	if len(bounds) != 2 {
+		return "", 0, 0, false
+	}
+	from, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	to, err = strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	if from < 0 || to < from {
+		return "", 0, 0, false
+	}
+	return unitAndRange[0], from, to, true
+}
@@ -0,0 +1,98 @@
+package sloth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ServerConfig configures the *http.Server used by StartWithConfig,
+// exposing the knobs of http.Server that Start's plain port number
+// does not.
+type ServerConfig struct {
+	// Addr is the TCP address to listen on, e.g. ":8080".
+	Addr string
+	// TLSConfig, if set, causes the server to accept only TLS
+	// connections configured accordingly, for example to require
+	// client certificates.
+	TLSConfig *tls.Config
+	// ReadTimeout, WriteTimeout and IdleTimeout mirror the
+	// corresponding fields of http.Server.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// MaxHeaderBytes mirrors http.Server.MaxHeaderBytes.
+	MaxHeaderBytes int
+	// BaseContext mirrors http.Server.BaseContext.
+	BaseContext func(net.Listener) context.Context
+}
+
+func (api *API) newServer(cfg ServerConfig) *http.Server {
+	return &http.Server{
+		Addr:           cfg.Addr,
+		Handler:        api.Mux(),
+		TLSConfig:      cfg.TLSConfig,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		IdleTimeout:    cfg.IdleTimeout,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+		BaseContext:    cfg.BaseContext,
+	}
+}
+
+// Start causes the API to begin serving requests on the given port.
+func (api *API) Start(port int) error {
+	return api.StartWithConfig(ServerConfig{Addr: fmt.Sprintf(":%d", port)})
+}
+
+// StartTLS causes the API to begin serving requests on addr using
+// TLS, loading the certificate and key from certFile and keyFile.
+func (api *API) StartTLS(addr, certFile, keyFile string) error {
+	if !api.muxInitialized {
+		return errors.New("You must add at least one resource to this API.")
+	}
+	server := api.newServer(ServerConfig{Addr: addr})
+	api.server = server
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
+
+// Serve causes the API to begin serving requests accepted from l,
+// instead of opening its own listener as Start does.
+func (api *API) Serve(l net.Listener) error {
+	if !api.muxInitialized {
+		return errors.New("You must add at least one resource to this API.")
+	}
+	server := api.newServer(ServerConfig{})
+	api.server = server
+	return server.Serve(l)
+}
+
+// StartWithConfig causes the API to begin serving requests according
+// to cfg, exposing the *http.Server knobs that Start does not, such
+// as timeouts and a *tls.Config for client-certificate authentication.
+func (api *API) StartWithConfig(cfg ServerConfig) error {
+	if !api.muxInitialized {
+		return errors.New("You must add at least one resource to this API.")
+	}
+	server := api.newServer(cfg)
+	api.server = server
+	if cfg.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// Shutdown gracefully shuts down the server started by Start,
+// StartTLS, Serve or StartWithConfig, letting requests already in
+// flight finish within the bounds of ctx. It is a thin wrapper around
+// http.Server.Shutdown.
+func (api *API) Shutdown(ctx context.Context) error {
+	if api.server == nil {
+		return errors.New("You must start the API before shutting it down.")
+	}
+	return api.server.Shutdown(ctx)
+}
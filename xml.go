@@ -0,0 +1,25 @@
+package sloth
+
+import (
+	"encoding/xml"
+)
+
+// XMLCodec is the built-in Codec that marshals response bodies as
+// indented XML. Register it with API.RegisterCodec to offer XML to
+// clients that ask for it via the Accept header.
+type XMLCodec struct{}
+
+// Marshal implements Codec.
+func (XMLCodec) Marshal(buf []byte, v interface{}) ([]byte, error) {
+	return xml.MarshalIndent(v, "", "  ")
+}
+
+// ContentType implements Codec.
+func (XMLCodec) ContentType() string {
+	return "application/xml"
+}
+
+// Accepts implements Codec.
+func (c XMLCodec) Accepts(mimeType string) bool {
+	return mimeMatches(c.ContentType(), mimeType)
+}